@@ -0,0 +1,198 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fbartkow321/interview/go/metrics"
+)
+
+// errCalamityQueueTimeout is sent to a queued request's result channel once
+// its max_wait_seconds deadline passes without the required heroes freeing
+// up.
+var errCalamityQueueTimeout = errors.New("calamity timed out waiting for heroes to become available")
+
+// schedulerPollInterval bounds how long a queued calamity can sit idle
+// between dispatch attempts when nothing nudges the scheduler directly
+// (e.g. a busy hold expiring doesn't currently call nudge).
+const schedulerPollInterval = 50 * time.Millisecond
+
+// queuedCalamityRequest is one entry in the calamity scheduler's queue.
+type queuedCalamityRequest struct {
+	calamity     calamity
+	entryForName map[string]*heroEntry
+	sortedNames  []string
+
+	hasDeadline bool
+	deadline    time.Time
+
+	result chan error
+}
+
+// calamityScheduler retries queued calamities in priority order (highest
+// required PowerLevel first) until their heroes are free or, if
+// max_wait_seconds was set, until the deadline passes.
+type calamityScheduler struct {
+	mu    sync.Mutex
+	queue []*queuedCalamityRequest
+	wake  chan struct{}
+}
+
+func newCalamityScheduler() *calamityScheduler {
+	s := &calamityScheduler{wake: make(chan struct{}, 1)}
+	go s.run()
+	return s
+}
+
+var scheduler = newCalamityScheduler()
+
+func (s *calamityScheduler) enqueue(req *queuedCalamityRequest) {
+	s.mu.Lock()
+	s.queue = append(s.queue, req)
+	s.sortLocked()
+	s.mu.Unlock()
+	s.nudge()
+}
+
+func (s *calamityScheduler) sortLocked() {
+	sort.SliceStable(s.queue, func(i, j int) bool {
+		return s.queue[i].calamity.PowerLevel > s.queue[j].calamity.PowerLevel
+	})
+}
+
+func (s *calamityScheduler) nudge() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *calamityScheduler) run() {
+	for {
+		select {
+		case <-s.wake:
+		case <-clock().After(schedulerPollInterval):
+		}
+		s.tick()
+	}
+}
+
+// tick attempts to dispatch every currently queued request, highest
+// priority first, and leaves whatever still can't be serviced (and hasn't
+// timed out) queued for the next tick.
+func (s *calamityScheduler) tick() {
+	s.mu.Lock()
+	items := s.queue
+	s.queue = nil
+	s.mu.Unlock()
+
+	now := clock().Now()
+	var stillQueued []*queuedCalamityRequest
+	for _, req := range items {
+		if req.hasDeadline && now.After(req.deadline) {
+			req.result <- errCalamityQueueTimeout
+			continue
+		}
+
+		ok, err := tryApplyCalamity(req.entryForName, req.sortedNames, req.calamity)
+		switch {
+		case err != nil:
+			req.result <- err
+		case ok:
+			req.result <- nil
+		default:
+			stillQueued = append(stillQueued, req)
+		}
+	}
+
+	s.mu.Lock()
+	s.queue = append(stillQueued, s.queue...)
+	s.sortLocked()
+	s.mu.Unlock()
+}
+
+// tryApplyCalamity makes a single non-blocking attempt to resolve c. ok is
+// true once it has been applied. A false, nil return means some required
+// hero's lock is currently held or busy and the caller should retry later;
+// a non-nil error means the calamity can never succeed (e.g. a hero is
+// dead, or the combined power level is insufficient).
+func tryApplyCalamity(entryForName map[string]*heroEntry, sortedNames []string, c calamity) (ok bool, err error) {
+	locked := make([]*heroEntry, 0, len(sortedNames))
+	defer func() {
+		for _, e := range locked {
+			e.mu.Unlock()
+		}
+	}()
+	for _, name := range sortedNames {
+		e := entryForName[name]
+		if !e.mu.TryLock() {
+			return false, nil
+		}
+		locked = append(locked, e)
+	}
+
+	if err := applyCalamity(entryForName, c); err != nil {
+		var busy *ErrHeroBusy
+		if errors.As(err, &busy) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// handleQueuedCalamity serves POST /calamity?mode=queue. It resolves c
+// immediately if possible; otherwise it enqueues c with the scheduler and
+// blocks until the scheduler resolves it, the client disconnects, or (if
+// MaxWaitSeconds was set) the deadline passes.
+func handleQueuedCalamity(w http.ResponseWriter, r *http.Request, c calamity) {
+	entryForName, sortedNames, err := calamityEntries(c.Heroes)
+	if err != nil {
+		httpError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if ok, err := tryApplyCalamity(entryForName, sortedNames, c); err != nil {
+		metrics.CalamityResolved("rejected")
+		httpError(w, r, http.StatusBadRequest, err.Error())
+		return
+	} else if ok {
+		metrics.CalamityResolved("resolved")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	req := &queuedCalamityRequest{
+		calamity:     c,
+		entryForName: entryForName,
+		sortedNames:  sortedNames,
+		result:       make(chan error, 1),
+	}
+	if c.MaxWaitSeconds > 0 {
+		req.hasDeadline = true
+		req.deadline = clock().Now().Add(time.Duration(c.MaxWaitSeconds) * time.Second)
+	}
+	scheduler.enqueue(req)
+
+	select {
+	case err := <-req.result:
+		if err == nil {
+			metrics.CalamityResolved("resolved")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if errors.Is(err, errCalamityQueueTimeout) {
+			metrics.CalamityResolved("timeout")
+			httpError(w, r, http.StatusRequestTimeout, err.Error())
+			return
+		}
+		metrics.CalamityResolved("rejected")
+		httpError(w, r, http.StatusBadRequest, err.Error())
+	case <-r.Context().Done():
+		// The client gave up waiting; the scheduler holds no reference to
+		// this response so there's nothing further to clean up here.
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clockSource whose After channels only fire when the test
+// explicitly advances it, making hold-time and scheduler-poll based
+// behavior deterministic to test.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(0, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	deadline := c.now.Add(d)
+	if !deadline.After(c.now) {
+		ch <- deadline
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeWaiter{deadline: deadline, ch: ch})
+	return ch
+}
+
+// Advance moves the clock forward by d and fires every waiter whose
+// deadline has passed.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	var fired, remaining []fakeWaiter
+	for _, w := range c.waiters {
+		if !w.deadline.After(c.now) {
+			fired = append(fired, w)
+		} else {
+			remaining = append(remaining, w)
+		}
+	}
+	c.waiters = remaining
+	c.mu.Unlock()
+
+	for _, w := range fired {
+		w.ch <- w.deadline
+	}
+}
+
+// waitUntil polls cond (with tiny real sleeps) until it becomes true, only
+// to give goroutines woken by the fake clock a chance to run; it never
+// waits on real elapsed time for the logic under test.
+func waitUntil(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("condition not met before deadline")
+}
+
+func TestCalamityHoldTimeBlocksMutationsThen423sWithRetryAfter(t *testing.T) {
+	fc := newFakeClock()
+	setClock(fc)
+	defer setClock(realClock{})
+
+	router := newTestRouter()
+	makeHero(t, router, "Atlas", 10)
+
+	body := `{"PowerLevel":5,"Heroes":["Atlas"],"DurationSeconds":10}`
+	req := httptest.NewRequest(http.MethodPost, "/calamity", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("calamity: expected 200, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	restReq := httptest.NewRequest(http.MethodPatch, "/hero/rest/Atlas", nil)
+	restRec := httptest.NewRecorder()
+	router.ServeHTTP(restRec, restReq)
+	if restRec.Code != http.StatusLocked {
+		t.Fatalf("expected 423 while busy, got %d %s", restRec.Code, restRec.Body.String())
+	}
+	if restRec.Header().Get("Retry-After") == "" {
+		t.Fatal("expected Retry-After header while hero is busy")
+	}
+
+	fc.Advance(10 * time.Second)
+
+	waitUntil(t, func() bool {
+		statusReq := httptest.NewRequest(http.MethodGet, "/v1/heroes/Atlas/status", nil)
+		statusRec := httptest.NewRecorder()
+		router.ServeHTTP(statusRec, statusReq)
+		return !strings.Contains(statusRec.Body.String(), `"busy":true`)
+	})
+}
+
+func TestQueuedCalamityDispatchesOnceHeroesFreeUp(t *testing.T) {
+	fc := newFakeClock()
+	setClock(fc)
+	defer setClock(realClock{})
+
+	router := newTestRouter()
+	makeHero(t, router, "Atlas", 15)
+	makeHero(t, router, "Bolt", 15)
+
+	holdBody := `{"PowerLevel":10,"Heroes":["Atlas"],"DurationSeconds":5}`
+	holdReq := httptest.NewRequest(http.MethodPost, "/calamity", strings.NewReader(holdBody))
+	holdRec := httptest.NewRecorder()
+	router.ServeHTTP(holdRec, holdReq)
+	if holdRec.Code != http.StatusOK {
+		t.Fatalf("initial calamity: expected 200, got %d %s", holdRec.Code, holdRec.Body.String())
+	}
+
+	queueBody := `{"PowerLevel":20,"Heroes":["Atlas","Bolt"]}`
+	queueReq := httptest.NewRequest(http.MethodPost, "/calamity?mode=queue", strings.NewReader(queueBody))
+	queueRec := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		router.ServeHTTP(queueRec, queueReq)
+		close(done)
+	}()
+
+	waitUntil(t, func() bool {
+		scheduler.mu.Lock()
+		defer scheduler.mu.Unlock()
+		return len(scheduler.queue) == 1
+	})
+
+	fc.Advance(5 * time.Second)
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("queued calamity was never dispatched after heroes freed up")
+	}
+
+	if queueRec.Code != http.StatusOK {
+		t.Fatalf("queued calamity: expected 200, got %d %s", queueRec.Code, queueRec.Body.String())
+	}
+}
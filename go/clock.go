@@ -0,0 +1,41 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// clockSource is the time source used anywhere a delay needs to be
+// observable from a test (busy/calamity hold-time, the calamity scheduler's
+// poll loop). Swapping the active clock for a fake in tests makes those
+// delays deterministic instead of racing real wall-clock sleeps.
+type clockSource interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// clockValue holds the active clockSource behind an atomic.Value rather
+// than a bare package variable: calamityScheduler.run() calls clock().After
+// in a loop for the lifetime of the process, and tests swap in a fakeClock
+// concurrently with that goroutine, which raced on a plain variable.
+var clockValue atomic.Value
+
+func init() {
+	clockValue.Store(clockSource(realClock{}))
+}
+
+// clock returns the currently active clockSource.
+func clock() clockSource {
+	return clockValue.Load().(clockSource)
+}
+
+// setClock installs c as the active clockSource. Tests use this to swap in
+// a fakeClock and restore realClock{} afterward.
+func setClock(c clockSource) {
+	clockValue.Store(c)
+}
@@ -1,14 +1,21 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"sort"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/fbartkow321/interview/go/metrics"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // PROBLEM DESCRIPTION:
@@ -51,6 +58,17 @@ import (
 var maxExhaustion = 10
 var resourceNotAvailableErr = "Resource not available - Cannot access Hero Data"
 
+// lockTimeout bounds how long a handler will wait to acquire a hero's lock
+// before giving up and reporting the resource as unavailable. Locking is now
+// per-hero (see heroStore below), so this replaces the old map-wide channel
+// timeout.
+const lockTimeout = 3 * time.Second
+
+// lockPollInterval is how often a blocked lock attempt re-checks whether its
+// context has expired. sync.RWMutex has no native context support, so
+// acquisition is a short poll loop guarded by the caller's context.
+const lockPollInterval = 5 * time.Millisecond
+
 type hero struct {
 	PowerLevel int    `json:"PowerLevel"`
 	Exhaustion int    `json:"Exhaustion"`
@@ -61,116 +79,347 @@ type hero struct {
 type calamity struct {
 	PowerLevel int      `json:"PowerLevel"`
 	Heroes     []string `json:"Heroes"`
+
+	// DurationSeconds, if set, holds the named heroes "busy" for that
+	// long after the calamity resolves (see applyCalamity and
+	// releaseBusyAfter). Zero means no hold time.
+	DurationSeconds int `json:"DurationSeconds,omitempty"`
+
+	// MaxWaitSeconds only applies to POST /calamity?mode=queue: how long
+	// the scheduler will keep retrying before giving up. Zero means wait
+	// indefinitely.
+	MaxWaitSeconds int `json:"max_wait_seconds,omitempty"`
 }
 
-var heroMapChannel chan map[string]hero
+// heroEntry owns a single hero's data behind its own RWMutex so that
+// operations on disjoint heroes never contend with one another.
+type heroEntry struct {
+	mu   sync.RWMutex
+	data hero
+
+	// busyUntil is non-zero while a calamity with a hold time is still
+	// occupying this hero. Callers must hold at least the read lock to
+	// consult it. Cleared by releaseBusyAfter once the hold expires.
+	busyUntil time.Time
+}
+
+// isBusy reports whether the entry is still held by a calamity's hold
+// time. The caller must hold at least the entry's read lock.
+func (e *heroEntry) isBusy() bool {
+	return !e.busyUntil.IsZero() && clock().Now().Before(e.busyUntil)
+}
+
+// rlock acquires the entry's read lock, giving up once ctx is done.
+func (e *heroEntry) rlock(ctx context.Context) bool {
+	for {
+		if e.mu.TryRLock() {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// lock acquires the entry's write lock, giving up once ctx is done.
+func (e *heroEntry) lock(ctx context.Context) bool {
+	for {
+		if e.mu.TryLock() {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// heroStore holds one heroEntry per hero name. The store's own mutex only
+// guards creation/removal of entries in the map; reading or mutating an
+// existing hero's data never touches it, which is what lets disjoint heroes
+// proceed concurrently.
+type heroStore struct {
+	mu      sync.RWMutex
+	entries map[string]*heroEntry
+}
+
+func newHeroStore() *heroStore {
+	return &heroStore{entries: make(map[string]*heroEntry)}
+}
+
+// entry returns the existing entry for name, if any.
+func (s *heroStore) entry(name string) (*heroEntry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.entries[name]
+	return e, ok
+}
+
+// entryOrCreate returns the entry for name, creating an empty one if this is
+// the first time name has been seen.
+func (s *heroStore) entryOrCreate(name string) *heroEntry {
+	s.mu.RLock()
+	e, ok := s.entries[name]
+	s.mu.RUnlock()
+	if ok {
+		return e
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok = s.entries[name]; ok {
+		return e
+	}
+	e = &heroEntry{}
+	s.entries[name] = e
+	return e
+}
 
+// remove drops a hero's entry entirely, e.g. on retirement.
+func (s *heroStore) remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, name)
+}
+
+var heroes = newHeroStore()
+
+// heroRepo is the storage backend every handler reads and writes through.
+// memoryHeroRepository is the only implementation; HeroRepository still
+// exists as a seam for a future backend, but calamity/status handling
+// reads the in-memory heroStore directly (see calamityEntries), so a
+// non-memory backend isn't swappable in here without wiring that up too.
+var heroRepo HeroRepository = newMemoryHeroRepository(heroes)
+
+// calamityEntries resolves every hero named by a calamity to its entry,
+// returning the names deduplicated and sorted for deterministic lock
+// acquisition order. Duplicate names are collapsed rather than locked twice:
+// sync.RWMutex isn't reentrant, so locking the same entry a second time
+// would just spin until lockTimeout and report a misleading 503.
+func calamityEntries(names []string) (map[string]*heroEntry, []string, error) {
+	sortedNames := append([]string(nil), names...)
+	sort.Strings(sortedNames)
+	sortedNames = dedupeSorted(sortedNames)
+
+	entryForName := make(map[string]*heroEntry, len(sortedNames))
+	for _, name := range sortedNames {
+		e, ok := heroes.entry(name)
+		if !ok {
+			return nil, nil, fmt.Errorf("Hero with name %q does not exist", name)
+		}
+		entryForName[name] = e
+	}
+	return entryForName, sortedNames, nil
+}
+
+// dedupeSorted collapses adjacent duplicates in a sorted slice in place.
+func dedupeSorted(sorted []string) []string {
+	if len(sorted) == 0 {
+		return sorted
+	}
+	out := sorted[:1]
+	for _, name := range sorted[1:] {
+		if name != out[len(out)-1] {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// applyCalamity validates that the heroes named by c can address it and, if
+// so, applies exhaustion/death and starts the calamity's hold time. The
+// caller must already hold every entry's write lock.
+func applyCalamity(entryForName map[string]*heroEntry, c calamity) error {
+	for name, e := range entryForName {
+		if !e.data.Alive {
+			return fmt.Errorf("Hero with name %q is dead and can no longer fight", name)
+		}
+		if e.isBusy() {
+			return &ErrHeroBusy{Until: e.busyUntil}
+		}
+	}
+
+	var totalPowerLevel int
+	heroesForCalamity := make([]hero, 0, len(entryForName))
+	for _, e := range entryForName {
+		totalPowerLevel += e.data.PowerLevel
+		heroesForCalamity = append(heroesForCalamity, e.data)
+	}
+	if c.PowerLevel > totalPowerLevel {
+		return errors.New("Powerlevel of calamity is higher than total powerlevel of all heroes. This calamity cannot be addressed.")
+	}
+
+	for _, h := range heroesForCalamity {
+		e := entryForName[h.Name]
+		h.Exhaustion++
+		if h.Exhaustion == maxExhaustion {
+			h.Alive = false
+			publishHeroEvent(eventExhausted, h.Name)
+			metrics.HeroDied(h.Name, h.Exhaustion)
+		} else {
+			metrics.HeroExhaustionChanged(h.Name, h.Exhaustion)
+		}
+		e.data = h
+	}
+
+	if c.DurationSeconds > 0 {
+		duration := time.Duration(c.DurationSeconds) * time.Second
+		until := clock().Now().Add(duration)
+		busyEntries := make([]*heroEntry, 0, len(entryForName))
+		for _, e := range entryForName {
+			if e.data.Alive {
+				e.busyUntil = until
+				busyEntries = append(busyEntries, e)
+			}
+		}
+		go releaseBusyAfter(busyEntries, until, duration)
+	}
+
+	for name := range entryForName {
+		publishHeroEvent(eventCalamityResolved, name)
+	}
+	return nil
+}
+
+// releaseBusyAfter waits out a calamity's hold time and then clears busy
+// status on every entry still marked busy with that exact deadline (a later
+// calamity may have already re-marked the hero busy, in which case that
+// later hold takes precedence).
+func releaseBusyAfter(entries []*heroEntry, until time.Time, d time.Duration) {
+	<-clock().After(d)
+	for _, e := range entries {
+		e.mu.Lock()
+		if e.busyUntil.Equal(until) {
+			e.busyUntil = time.Time{}
+		}
+		e.mu.Unlock()
+	}
+}
+
+// handleCalamity locks heroes directly on the in-memory heroStore rather
+// than through heroRepo: resolving a calamity needs several heroes locked
+// together atomically, which HeroRepository has no way to express.
 func handleCalamity(w http.ResponseWriter, r *http.Request) {
 	content, readErr := ioutil.ReadAll(r.Body)
 	if readErr != nil {
-		http.Error(w, readErr.Error(), http.StatusInternalServerError)
+		httpError(w, r, http.StatusInternalServerError, readErr.Error())
 		return
 	}
-	var calamity calamity
-	unmarshalErr := json.Unmarshal(content, &calamity)
+	var c calamity
+	unmarshalErr := json.Unmarshal(content, &c)
 	if unmarshalErr != nil {
-		http.Error(w, unmarshalErr.Error(), http.StatusInternalServerError)
+		httpError(w, r, http.StatusInternalServerError, unmarshalErr.Error())
 		return
 	}
-	if len(calamity.Heroes) < 1 {
-		http.Error(w, "Must designate one or more heroes to address the calamity", http.StatusInternalServerError)
+	if len(c.Heroes) < 1 {
+		httpError(w, r, http.StatusInternalServerError, "Must designate one or more heroes to address the calamity")
 		return
 	}
 
-	mapOfHeroes := attemptToGetHeroData()
-	if mapOfHeroes == nil {
-		http.Error(w, resourceNotAvailableErr, http.StatusInternalServerError)
+	if r.URL.Query().Get("mode") == "queue" {
+		handleQueuedCalamity(w, r, c)
 		return
 	}
 
-	var totalPowerLevel int
-	var heroesForCalamity []hero
-	var err error
-	totalPowerLevel, heroesForCalamity, err = compileHeroData(calamity.Heroes, mapOfHeroes)
+	entryForName, sortedNames, err := calamityEntries(c.Heroes)
 	if err != nil {
-		heroMapChannel <- mapOfHeroes
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		httpError(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if calamity.PowerLevel > totalPowerLevel {
-		heroMapChannel <- mapOfHeroes
-		errMessage := "Powerlevel of calamity is higher than total powerlevel of all heroes. This calamity cannot be addressed."
-		http.Error(w, errMessage, http.StatusBadRequest)
-		return
+	ctx, cancel := context.WithTimeout(r.Context(), lockTimeout)
+	defer cancel()
+
+	locked := make([]*heroEntry, 0, len(sortedNames))
+	defer func() {
+		for _, e := range locked {
+			e.mu.Unlock()
+		}
+	}()
+	for _, name := range sortedNames {
+		e := entryForName[name]
+		if !e.lock(ctx) {
+			httpError(w, r, http.StatusServiceUnavailable, resourceNotAvailableErr)
+			return
+		}
+		locked = append(locked, e)
 	}
 
-	for _, hero := range heroesForCalamity {
-		hero.Exhaustion++
-		if hero.Exhaustion == maxExhaustion {
-			hero.Alive = false
+	if err := applyCalamity(entryForName, c); err != nil {
+		var busy *ErrHeroBusy
+		if errors.As(err, &busy) {
+			retryAfter := int(busy.Until.Sub(clock().Now()).Seconds())
+			if retryAfter < 0 {
+				retryAfter = 0
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			metrics.CalamityResolved("busy")
+			httpError(w, r, http.StatusLocked, err.Error())
+			return
 		}
-		mapOfHeroes[hero.Name] = hero
+		metrics.CalamityResolved("rejected")
+		httpError(w, r, http.StatusBadRequest, err.Error())
+		return
 	}
-	heroMapChannel <- mapOfHeroes
+	metrics.CalamityResolved("resolved")
 	w.WriteHeader(http.StatusOK)
-
 }
 
-// Loops through the list of names and pulls heroes off of the map. Each hero's powerlevel is added to the
-// total powerlevel, and each hero is added to an array of heroes. At the end of the method, the total powerlevel
-// and array of heroes are returned.
-//
-// If there is an issue obtaining the data, the error this function returns will not be nil.
-func compileHeroData(names []string, mapOfHeroes map[string]hero) (int, []hero, error) {
-	var heroesForCalamity []hero
-	var totalPowerLevel int
-	for _, heroName := range names {
-		var hero hero
-		var heroExists bool
-		if hero, heroExists = mapOfHeroes[heroName]; !heroExists {
-			return 0, nil, fmt.Errorf("Hero with name %q does not exist", heroName)
-		}
-		if !hero.Alive {
-			return 0, nil, fmt.Errorf("Hero with name %q is dead and can no longer fight", heroName)
+// writeRepoError translates a HeroRepository error into the HTTP response
+// the handlers have always returned for that condition.
+func writeRepoError(w http.ResponseWriter, r *http.Request, name string, err error) {
+	var busy *ErrHeroBusy
+	switch {
+	case errors.Is(err, ErrHeroNotFound):
+		httpError(w, r, http.StatusNotFound, fmt.Sprintf("Hero with name %q does not exist", name))
+	case errors.Is(err, errHeroAlreadyDead):
+		httpError(w, r, http.StatusBadRequest, fmt.Sprintf("Hero with name %q has already been killed, and thus cannot be killed again", name))
+	case errors.Is(err, errHeroNotAlive):
+		httpError(w, r, http.StatusBadRequest, fmt.Sprintf("Hero with name %q is dead, and thus can not rest", name))
+	case errors.Is(err, errNoExhaustion):
+		httpError(w, r, http.StatusBadRequest, fmt.Sprintf("Hero with name %q does not need rest", name))
+	case errors.As(err, &busy):
+		retryAfter := int(busy.Until.Sub(clock().Now()).Seconds())
+		if retryAfter < 0 {
+			retryAfter = 0
 		}
-		totalPowerLevel += hero.PowerLevel
-		heroesForCalamity = append(heroesForCalamity, hero)
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		errMessage := fmt.Sprintf("Hero with name %q is busy addressing a calamity until %s", name, busy.Until.Format(time.RFC3339))
+		httpError(w, r, http.StatusLocked, errMessage)
+	case errors.Is(err, ErrLockUnavailable), errors.Is(err, context.DeadlineExceeded):
+		httpError(w, r, http.StatusServiceUnavailable, resourceNotAvailableErr)
+	default:
+		httpError(w, r, http.StatusInternalServerError, err.Error())
 	}
-	return totalPowerLevel, heroesForCalamity, nil
 }
 
 func heroKill(w http.ResponseWriter, r *http.Request) {
 	var name string
 	var ok bool
 	if name, ok = mux.Vars(r)["name"]; !ok {
-		http.Error(w, "A name must be provided (ex: /hero/kill/{name})", http.StatusBadRequest)
+		httpError(w, r, http.StatusBadRequest, "A name must be provided (ex: /hero/kill/{name})")
 		return
 	}
 
-	mapOfHeroes := attemptToGetHeroData()
-	if mapOfHeroes == nil {
-		http.Error(w, resourceNotAvailableErr, http.StatusInternalServerError)
-		return
-	}
-
-	var hero hero
-	if hero, ok = mapOfHeroes[name]; !ok {
-		heroMapChannel <- mapOfHeroes
-		errMessage := fmt.Sprintf("Hero with name %q does not exist", name)
-		http.Error(w, errMessage, http.StatusNotFound)
-		return
-	}
-	if !hero.Alive {
-		heroMapChannel <- mapOfHeroes
-		errMessage := fmt.Sprintf("Hero with name %q has already been killed, and thus cannot be killed again", name)
-		http.Error(w, errMessage, http.StatusBadRequest)
+	ctx, cancel := context.WithTimeout(r.Context(), lockTimeout)
+	defer cancel()
+	err := heroRepo.Update(ctx, name, func(h *hero) error {
+		if !h.Alive {
+			return errHeroAlreadyDead
+		}
+		h.Alive = false
+		return nil
+	})
+	if err != nil {
+		writeRepoError(w, r, name, err)
 		return
 	}
 
-	hero.Alive = false
-	mapOfHeroes[name] = hero
-	heroMapChannel <- mapOfHeroes
+	publishHeroEvent(eventKilled, name)
+	metrics.HeroKilled(name)
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -178,31 +427,27 @@ func heroRetire(w http.ResponseWriter, r *http.Request) {
 	var name string
 	var ok bool
 	if name, ok = mux.Vars(r)["name"]; !ok {
-		http.Error(w, "A name must be provided (ex: /hero/{name})", http.StatusBadRequest)
+		httpError(w, r, http.StatusBadRequest, "A name must be provided (ex: /hero/{name})")
 		return
 	}
 
-	mapOfHeroes := attemptToGetHeroData()
-	if mapOfHeroes == nil {
-		http.Error(w, resourceNotAvailableErr, http.StatusInternalServerError)
+	ctx, cancel := context.WithTimeout(r.Context(), lockTimeout)
+	defer cancel()
+	// RetireIfAlive checks aliveness and removes the hero as one atomic
+	// operation, so a concurrent kill can't land in the gap between a
+	// separate check and a separate delete.
+	if err := heroRepo.RetireIfAlive(ctx, name); err != nil {
+		if errors.Is(err, errHeroNotAlive) {
+			errMessage := fmt.Sprintf("Hero with name %q has been killed, and thus cannot retire", name)
+			httpError(w, r, http.StatusBadRequest, errMessage)
+			return
+		}
+		writeRepoError(w, r, name, err)
 		return
 	}
 
-	var hero hero
-	if hero, ok = mapOfHeroes[name]; !ok {
-		heroMapChannel <- mapOfHeroes
-		errMessage := fmt.Sprintf("Hero with name %q does not exist", name)
-		http.Error(w, errMessage, http.StatusNotFound)
-		return
-	}
-	if !hero.Alive {
-		heroMapChannel <- mapOfHeroes
-		errMessage := fmt.Sprintf("Hero with name %q has been killed, and thus cannot retire", name)
-		http.Error(w, errMessage, http.StatusBadRequest)
-		return
-	}
-	delete(mapOfHeroes, name)
-	heroMapChannel <- mapOfHeroes
+	publishHeroEvent(eventRetired, name)
+	metrics.HeroRetired(name)
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -210,38 +455,31 @@ func heroRest(w http.ResponseWriter, r *http.Request) {
 	var name string
 	var ok bool
 	if name, ok = mux.Vars(r)["name"]; !ok {
-		http.Error(w, "A name must be provided (ex: /hero/rest/{name})", http.StatusBadRequest)
+		httpError(w, r, http.StatusBadRequest, "A name must be provided (ex: /hero/rest/{name})")
 		return
 	}
 
-	mapOfHeroes := attemptToGetHeroData()
-	if mapOfHeroes == nil {
-		http.Error(w, resourceNotAvailableErr, http.StatusInternalServerError)
+	ctx, cancel := context.WithTimeout(r.Context(), lockTimeout)
+	defer cancel()
+	var exhaustion int
+	err := heroRepo.Update(ctx, name, func(h *hero) error {
+		if !h.Alive {
+			return errHeroNotAlive
+		}
+		if h.Exhaustion == 0 {
+			return errNoExhaustion
+		}
+		h.Exhaustion--
+		exhaustion = h.Exhaustion
+		return nil
+	})
+	if err != nil {
+		writeRepoError(w, r, name, err)
 		return
 	}
 
-	var hero hero
-	if hero, ok = mapOfHeroes[name]; !ok {
-		heroMapChannel <- mapOfHeroes
-		errMessage := fmt.Sprintf("Hero with name %q does not exist", name)
-		http.Error(w, errMessage, http.StatusNotFound)
-		return
-	}
-	if !hero.Alive {
-		heroMapChannel <- mapOfHeroes
-		errMessage := fmt.Sprintf("Hero with name %q is dead, and thus can not rest", name)
-		http.Error(w, errMessage, http.StatusBadRequest)
-		return
-	}
-	if hero.Exhaustion == 0 {
-		heroMapChannel <- mapOfHeroes
-		errMessage := fmt.Sprintf("Hero with name %q does not need rest", name)
-		http.Error(w, errMessage, http.StatusBadRequest)
-		return
-	}
-	hero.Exhaustion--
-	mapOfHeroes[name] = hero
-	heroMapChannel <- mapOfHeroes
+	publishHeroEvent(eventRested, name)
+	metrics.HeroExhaustionChanged(name, exhaustion)
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -249,29 +487,21 @@ func heroGet(w http.ResponseWriter, r *http.Request) {
 	var name string
 	var ok bool
 	if name, ok = mux.Vars(r)["name"]; !ok {
-		http.Error(w, "A name must be provided (ex: /hero/{name})", http.StatusBadRequest)
+		httpError(w, r, http.StatusBadRequest, "A name must be provided (ex: /hero/{name})")
 		return
 	}
 
-	mapOfHeroes := attemptToGetHeroData()
-	if mapOfHeroes == nil {
-		http.Error(w, resourceNotAvailableErr, http.StatusInternalServerError)
-		return
-	}
-
-	var hero hero
-	hero, ok = mapOfHeroes[name]
-	// Return map to channel ASAP to prevent blocking other threads
-	heroMapChannel <- mapOfHeroes
-	if !ok {
-		errMessage := fmt.Sprintf("Hero with name %q does not exist", name)
-		http.Error(w, errMessage, http.StatusNotFound)
+	ctx, cancel := context.WithTimeout(r.Context(), lockTimeout)
+	defer cancel()
+	h, err := singleflightGet(ctx, name)
+	if err != nil {
+		writeRepoError(w, r, name, err)
 		return
 	}
 
-	js, err := json.Marshal(hero)
+	js, err := json.Marshal(h)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		httpError(w, r, http.StatusInternalServerError, err.Error())
 		return
 	}
 
@@ -283,56 +513,44 @@ func heroGet(w http.ResponseWriter, r *http.Request) {
 func heroMake(w http.ResponseWriter, r *http.Request) {
 	content, readErr := ioutil.ReadAll(r.Body)
 	if readErr != nil {
-		http.Error(w, readErr.Error(), http.StatusInternalServerError)
+		httpError(w, r, http.StatusInternalServerError, readErr.Error())
 		return
 	}
-	var hero hero
-	unmarshalErr := json.Unmarshal(content, &hero)
+	var h hero
+	unmarshalErr := json.Unmarshal(content, &h)
 	if unmarshalErr != nil {
-		http.Error(w, unmarshalErr.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	mapOfHeroes := attemptToGetHeroData()
-	if mapOfHeroes == nil {
-		http.Error(w, resourceNotAvailableErr, http.StatusInternalServerError)
+		httpError(w, r, http.StatusInternalServerError, unmarshalErr.Error())
 		return
 	}
 
-	if hero, heroExists := mapOfHeroes[hero.Name]; heroExists {
-		heroMapChannel <- mapOfHeroes
-		if hero.Alive {
-			errMessage := fmt.Sprintf("Hero with name %q already exists", hero.Name)
-			http.Error(w, errMessage, http.StatusConflict)
-		} else {
-			errMessage := fmt.Sprintf("A hero named %q once died valiantly in battle, and their name shall not be taken", hero.Name)
-			http.Error(w, errMessage, http.StatusConflict)
+	ctx, cancel := context.WithTimeout(r.Context(), lockTimeout)
+	defer cancel()
+	h.Alive = true
+	err := heroRepo.Insert(ctx, h)
+	if err != nil {
+		var exists *ErrHeroExists
+		if errors.As(err, &exists) {
+			if exists.Existing.Alive {
+				errMessage := fmt.Sprintf("Hero with name %q already exists", h.Name)
+				httpError(w, r, http.StatusConflict, errMessage)
+			} else {
+				errMessage := fmt.Sprintf("A hero named %q once died valiantly in battle, and their name shall not be taken", h.Name)
+				httpError(w, r, http.StatusConflict, errMessage)
+			}
+			return
 		}
+		writeRepoError(w, r, h.Name, err)
 		return
 	}
-	hero.Alive = true
-	mapOfHeroes[hero.Name] = hero
-	heroMapChannel <- mapOfHeroes
-	w.WriteHeader(http.StatusOK)
-}
 
-// This method will try to receive the map from the channel and then subsequently return it.
-// If the channel fails to produce the map within three seconds, this method will stop trying
-// to receive the map and simply return a nil value instead.
-//
-// If this method is successful in returning the map of hero data, then the code that follows
-// must send the map back into channel at some point. Failure to do so will result in a loss
-// of all data and the service will no longer be usable.
-func attemptToGetHeroData() map[string]hero {
-	select {
-	case mapOfHeroes := <-heroMapChannel:
-		return mapOfHeroes
-	case <-time.After(3 * time.Second):
-		return nil
-	}
+	publishHeroEvent(eventCreated, h.Name)
+	metrics.HeroCreated(h.Name)
+	w.WriteHeader(http.StatusOK)
 }
 
 func linkRoutes(r *mux.Router) {
+	r.Use(observabilityMiddleware)
+
 	// It might be more accurrate to make this a "PUT" due to it's idempotence
 	r.HandleFunc("/hero", heroMake).Methods("POST")
 
@@ -345,13 +563,17 @@ func linkRoutes(r *mux.Router) {
 	r.HandleFunc("/hero/{name}", heroRetire).Methods("DELETE")
 
 	r.HandleFunc("/calamity", handleCalamity).Methods("POST")
+
+	r.HandleFunc("/v1/heroes", handleHeroList).Methods("GET")
+	r.HandleFunc("/v1/heroes/{name}/status", handleHeroStatus).Methods("GET")
+
+	r.HandleFunc("/v1/trace", handleTraceSSE).Methods("GET")
+	r.HandleFunc("/v1/trace/ws", handleTraceWS).Methods("GET")
+
+	r.Handle("/metrics", promhttp.Handler()).Methods("GET")
 }
 
 func main() {
-	// Initalize channel & map to hold the heroes data
-	heroMapChannel = make(chan map[string]hero, 1)
-	heroMapChannel <- make(map[string]hero)
-
 	// Create a router
 	router := mux.NewRouter()
 
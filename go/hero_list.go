@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const defaultPageSize = 20
+
+// maxPage and maxPageSize bound the page/page_size query parameters so
+// List's pagination math ((page-1)*pageSize, plus pageSize) can't overflow
+// int and hand memoryHeroRepository.List a negative slice bound.
+const (
+	maxPage     = 1_000_000
+	maxPageSize = 1_000
+)
+
+type heroListMetadata struct {
+	CurrentPage  int `json:"current_page"`
+	PageSize     int `json:"page_size"`
+	TotalRecords int `json:"total_records"`
+}
+
+type heroListResponse struct {
+	Metadata heroListMetadata `json:"metadata"`
+	Heroes   []hero           `json:"heroes"`
+}
+
+// handleHeroList serves GET /v1/heroes?name=&minPower=&alive=&page=&page_size=&sort=.
+func handleHeroList(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	filter := HeroFilter{NameContains: q.Get("name")}
+	if raw := q.Get("minPower"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			httpError(w, r, http.StatusBadRequest, "minPower must be an integer")
+			return
+		}
+		filter.MinPower = v
+	}
+	if raw := q.Get("alive"); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			httpError(w, r, http.StatusBadRequest, "alive must be true or false")
+			return
+		}
+		filter.Alive = &v
+	}
+
+	page := 1
+	if raw := q.Get("page"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxPage {
+			httpError(w, r, http.StatusBadRequest, fmt.Sprintf("page must be an integer between 1 and %d", maxPage))
+			return
+		}
+		page = v
+	}
+
+	pageSize := defaultPageSize
+	if raw := q.Get("page_size"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxPageSize {
+			httpError(w, r, http.StatusBadRequest, fmt.Sprintf("page_size must be an integer between 1 and %d", maxPageSize))
+			return
+		}
+		pageSize = v
+	}
+
+	sortToken := q.Get("sort")
+	if sortToken == "" {
+		sortToken = "name"
+	}
+	if _, ok := heroSortFields[strings.TrimPrefix(sortToken, "-")]; !ok {
+		errMessage := fmt.Sprintf("sort must be one of name, powerlevel, exhaustion, optionally prefixed with '-'; got %q", sortToken)
+		httpError(w, r, http.StatusBadRequest, errMessage)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), lockTimeout)
+	defer cancel()
+	result, err := singleflightList(ctx, filter, page, pageSize, sortToken)
+	if err != nil {
+		if errors.Is(err, ErrLockUnavailable) || errors.Is(err, context.DeadlineExceeded) {
+			httpError(w, r, http.StatusServiceUnavailable, resourceNotAvailableErr)
+		} else {
+			httpError(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	response := heroListResponse{
+		Metadata: heroListMetadata{
+			CurrentPage:  page,
+			PageSize:     pageSize,
+			TotalRecords: result.TotalRecords,
+		},
+		Heroes: result.Heroes,
+	}
+	if response.Heroes == nil {
+		response.Heroes = []hero{}
+	}
+
+	js, err := json.Marshal(response)
+	if err != nil {
+		httpError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(js)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHeroListFilterPaginationAndSort(t *testing.T) {
+	router := newTestRouter()
+	makeHero(t, router, "Alpha", 5)
+	makeHero(t, router, "Beta", 20)
+	makeHero(t, router, "Gamma", 10)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/heroes?minPower=8&page=1&page_size=1&sort=-powerlevel", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	var resp heroListResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Metadata.TotalRecords != 2 {
+		t.Fatalf("expected 2 total records (Beta, Gamma), got %d", resp.Metadata.TotalRecords)
+	}
+	if len(resp.Heroes) != 1 || resp.Heroes[0].Name != "Beta" {
+		t.Fatalf("expected first page to contain Beta (highest power), got %+v", resp.Heroes)
+	}
+}
+
+func TestHeroListRejectsUnknownSortField(t *testing.T) {
+	router := newTestRouter()
+	req := httptest.NewRequest(http.MethodGet, "/v1/heroes?sort=favoriteColor", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown sort field, got %d", rec.Code)
+	}
+}
+
+// TestHeroListRejectsOversizedPageParams guards against page/page_size
+// values large enough to overflow List's pagination math.
+func TestHeroListRejectsOversizedPageParams(t *testing.T) {
+	router := newTestRouter()
+	makeHero(t, router, "Atlas", 10)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/heroes?page=2&page_size=9223372036854775807", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an oversized page_size, got %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type heroStatusResponse struct {
+	Busy       bool       `json:"busy"`
+	BusyUntil  *time.Time `json:"busy_until,omitempty"`
+	Exhaustion int        `json:"exhaustion"`
+	Alive      bool       `json:"alive"`
+}
+
+// handleHeroStatus serves GET /v1/heroes/{name}/status, mainly so a caller
+// can tell whether a hero is still held by a calamity's hold time before
+// trying (and getting 423'd by) a mutating endpoint.
+func handleHeroStatus(w http.ResponseWriter, r *http.Request) {
+	name, ok := mux.Vars(r)["name"]
+	if !ok {
+		httpError(w, r, http.StatusBadRequest, "A name must be provided (ex: /v1/heroes/{name}/status)")
+		return
+	}
+
+	entry, ok := heroes.entry(name)
+	if !ok {
+		httpError(w, r, http.StatusNotFound, fmt.Sprintf("Hero with name %q does not exist", name))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), lockTimeout)
+	defer cancel()
+	if !entry.rlock(ctx) {
+		httpError(w, r, http.StatusServiceUnavailable, resourceNotAvailableErr)
+		return
+	}
+	status := heroStatusResponse{
+		Busy:       entry.isBusy(),
+		Exhaustion: entry.data.Exhaustion,
+		Alive:      entry.data.Alive,
+	}
+	if status.Busy {
+		until := entry.busyUntil
+		status.BusyUntil = &until
+	}
+	entry.mu.RUnlock()
+
+	js, err := json.Marshal(status)
+	if err != nil {
+		httpError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(js)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/sync/singleflight"
+)
+
+func newTestRouter() *mux.Router {
+	heroes = newHeroStore()
+	heroRepo = newMemoryHeroRepository(heroes)
+	heroGetGroup = &singleflight.Group{}
+	heroListGroup = &singleflight.Group{}
+	heroGetCalls = newDedupCallTracker()
+	heroListCalls = newDedupCallTracker()
+	atomic.StoreUint64(&heroesSingleflightSharedTotal, 0)
+	atomic.StoreUint64(&heroesSingleflightLeaderTotal, 0)
+	router := mux.NewRouter()
+	linkRoutes(router)
+	return router
+}
+
+func makeHero(t *testing.T, router *mux.Router, name string, power int) {
+	t.Helper()
+	body := `{"PowerLevel":` + strconv.Itoa(power) + `,"Name":"` + name + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/hero", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("failed to create hero %q: %d %s", name, rec.Code, rec.Body.String())
+	}
+}
+
+// TestConcurrentRestOnDisjointHeroesRunsInParallel fires many /hero/rest
+// requests at many different heroes at once and checks that every one gets
+// its expected 400 and that the whole batch finishes within one second. It
+// does not time a serial run of the same requests to compare against.
+func TestConcurrentRestOnDisjointHeroesRunsInParallel(t *testing.T) {
+	router := newTestRouter()
+
+	const heroCount = 200
+	names := make([]string, heroCount)
+	for i := 0; i < heroCount; i++ {
+		names[i] = "Hero" + strconv.Itoa(i)
+		makeHero(t, router, names[i], 10)
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPatch, "/hero/rest/"+name, nil)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("rest on fresh hero %q: expected 400 (no exhaustion), got %d", name, rec.Code)
+			}
+		}(name)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	if elapsed > time.Second {
+		t.Fatalf("expected concurrent /hero/rest calls on disjoint heroes to finish quickly, took %s", elapsed)
+	}
+}
+
+// TestCalamityLocksHeroesInSortedOrder exercises overlapping calamities
+// against a shared subset of heroes and checks that none of them time out
+// waiting on a lock, which they would if acquisition order weren't
+// deterministic.
+func TestCalamityLocksHeroesInSortedOrder(t *testing.T) {
+	router := newTestRouter()
+	makeHero(t, router, "Alpha", 10)
+	makeHero(t, router, "Beta", 10)
+	makeHero(t, router, "Gamma", 10)
+
+	bodies := []string{
+		`{"PowerLevel":5,"Heroes":["Gamma","Alpha"]}`,
+		`{"PowerLevel":5,"Heroes":["Alpha","Beta"]}`,
+		`{"PowerLevel":5,"Heroes":["Beta","Gamma"]}`,
+	}
+
+	var wg sync.WaitGroup
+	for _, body := range bodies {
+		wg.Add(1)
+		go func(body string) {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPost, "/calamity", strings.NewReader(body))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Errorf("calamity %s: expected 200, got %d %s", body, rec.Code, rec.Body.String())
+			}
+		}(body)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,83 @@
+// Package metrics holds the process's Prometheus collectors and the narrow
+// set of functions the rest of the server calls to update them, so hero.go
+// and friends never create or update collectors directly (hero.go only
+// mounts promhttp's handler at /metrics).
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	heroesTotal = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "heroes_total",
+		Help: "Number of tracked heroes, partitioned by whether they are alive.",
+	}, []string{"alive"})
+
+	heroExhaustion = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "hero_exhaustion",
+		Help: "Current exhaustion level of each tracked hero.",
+	}, []string{"name"})
+
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Total HTTP requests handled, partitioned by route, method, and status.",
+	}, []string{"route", "method", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency in seconds, partitioned by route and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	calamitiesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "calamities_total",
+		Help: "Total calamities handled, partitioned by outcome.",
+	}, []string{"outcome"})
+)
+
+// HeroCreated records a newly made hero.
+func HeroCreated(name string) {
+	heroesTotal.WithLabelValues("true").Inc()
+	heroExhaustion.WithLabelValues(name).Set(0)
+}
+
+// HeroExhaustionChanged updates name's current exhaustion gauge.
+func HeroExhaustionChanged(name string, exhaustion int) {
+	heroExhaustion.WithLabelValues(name).Set(float64(exhaustion))
+}
+
+// HeroDied records a hero who reached max exhaustion during a calamity.
+func HeroDied(name string, exhaustion int) {
+	heroesTotal.WithLabelValues("true").Dec()
+	heroesTotal.WithLabelValues("false").Inc()
+	heroExhaustion.WithLabelValues(name).Set(float64(exhaustion))
+}
+
+// HeroKilled records a hero killed outright via /hero/kill/{name}.
+func HeroKilled(name string) {
+	heroesTotal.WithLabelValues("true").Dec()
+	heroesTotal.WithLabelValues("false").Inc()
+}
+
+// HeroRetired records a retired hero, dropping its exhaustion gauge since
+// the name is no longer tracked at all.
+func HeroRetired(name string) {
+	heroesTotal.WithLabelValues("true").Dec()
+	heroExhaustion.DeleteLabelValues(name)
+}
+
+// HTTPRequest records one completed request's outcome and latency.
+func HTTPRequest(route, method, status string, duration time.Duration) {
+	httpRequestsTotal.WithLabelValues(route, method, status).Inc()
+	httpRequestDuration.WithLabelValues(route, method).Observe(duration.Seconds())
+}
+
+// CalamityResolved records a calamity's terminal outcome, e.g. "resolved",
+// "rejected", "busy", or "timeout".
+func CalamityResolved(outcome string) {
+	calamitiesTotal.WithLabelValues(outcome).Inc()
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fbartkow321/interview/go/metrics"
+	"github.com/gorilla/mux"
+)
+
+// requestIDHeader is both the header a client can supply a request ID on
+// and the one the server echoes it back (or a generated one) on.
+const requestIDHeader = "X-Request-ID"
+
+type contextKey string
+
+const requestIDContextKey contextKey = "requestID"
+
+// requestIDFromContext returns the current request's ID, or "" if none is
+// set (e.g. outside of a request handled through observabilityMiddleware).
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+// newRequestID returns a random 32-character hex string, or the current
+// time in nanoseconds as hex if the random source fails.
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// httpError writes message as the response body with status, tagging it
+// with the request's ID so it can be correlated with the structured log
+// line observabilityMiddleware emits for the same request.
+func httpError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if reqID := requestIDFromContext(r.Context()); reqID != "" {
+		message = fmt.Sprintf("%s (request_id=%s)", message, reqID)
+	}
+	http.Error(w, message, status)
+}
+
+// responseWriter wraps http.ResponseWriter to capture the status code
+// written, since net/http gives handlers no way to read it back afterward.
+// It forwards http.Flusher and http.Hijacker to the wrapped writer when
+// those are supported, since observabilityMiddleware wraps every handler
+// including /v1/trace (SSE, needs Flush) and /v1/trace/ws (needs Hijack).
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.status = status
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher by delegating to the wrapped writer, if it
+// supports flushing. Required for /v1/trace's SSE stream to deliver events
+// as they're published rather than only once the connection closes.
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker by delegating to the wrapped writer, if
+// it supports hijacking. Required for /v1/trace/ws's WebSocket upgrade,
+// which takes over the raw connection itself.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("responseWriter: underlying ResponseWriter does not support hijacking")
+	}
+	return hj.Hijack()
+}
+
+// observabilityMiddleware assigns (or propagates) a request ID, records
+// http_requests_total/http_request_duration_seconds, and emits one
+// structured log line per request.
+func observabilityMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDHeader)
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+		r = r.WithContext(context.WithValue(r.Context(), requestIDContextKey, requestID))
+
+		rw := &responseWriter{ResponseWriter: w}
+		start := time.Now()
+		next.ServeHTTP(rw, r)
+		duration := time.Since(start)
+
+		route := "unmatched"
+		if mr := mux.CurrentRoute(r); mr != nil {
+			if tmpl, err := mr.GetPathTemplate(); err == nil {
+				route = tmpl
+			}
+		}
+
+		metrics.HTTPRequest(route, r.Method, strconv.Itoa(rw.status), duration)
+
+		slog.Info("request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rw.status,
+			"duration_ms", duration.Milliseconds(),
+			"remote_addr", r.RemoteAddr,
+			"request_id", requestID,
+		)
+	})
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestResponseWriterForwardsFlusherAndHijacker guards against regressing
+// /v1/trace (SSE, needs http.Flusher) and /v1/trace/ws (needs
+// http.Hijacker) once they're wrapped by observabilityMiddleware.
+func TestResponseWriterForwardsFlusherAndHijacker(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rw := &responseWriter{ResponseWriter: rec}
+
+	if _, ok := interface{}(rw).(http.Flusher); !ok {
+		t.Fatal("responseWriter must implement http.Flusher")
+	}
+	rw.Flush()
+	if !rec.Flushed {
+		t.Fatal("expected Flush to be forwarded to the underlying ResponseWriter")
+	}
+
+	if _, ok := interface{}(rw).(http.Hijacker); !ok {
+		t.Fatal("responseWriter must implement http.Hijacker")
+	}
+	if _, _, err := rw.Hijack(); err == nil {
+		t.Fatal("expected Hijack to fail against an httptest.ResponseRecorder, which doesn't support it")
+	}
+}
+
+// TestObservabilityMiddlewareGeneratesAndEchoesRequestID checks that a
+// caller's own X-Request-ID is echoed back, and that one is generated when
+// absent.
+func TestObservabilityMiddlewareGeneratesAndEchoesRequestID(t *testing.T) {
+	router := newTestRouter()
+	makeHero(t, router, "Nova", 10)
+
+	req := httptest.NewRequest(http.MethodGet, "/hero/Nova", nil)
+	req.Header.Set(requestIDHeader, "caller-supplied-id")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if got := rec.Header().Get(requestIDHeader); got != "caller-supplied-id" {
+		t.Fatalf("expected the caller-supplied request ID to be echoed back, got %q", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/hero/Nova", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if got := rec.Header().Get(requestIDHeader); got == "" {
+		t.Fatal("expected a request ID to be generated when the client didn't supply one")
+	}
+}
+
+// TestMetricsEndpointReportsHeroAndRequestCounters exercises a few handlers
+// and checks that GET /metrics reflects both the hero lifecycle and HTTP
+// request counters they should have updated.
+func TestMetricsEndpointReportsHeroAndRequestCounters(t *testing.T) {
+	router := newTestRouter()
+	makeHero(t, router, "Comet", 10)
+
+	getReq := httptest.NewRequest(http.MethodGet, "/hero/Comet", nil)
+	getRec := httptest.NewRecorder()
+	router.ServeHTTP(getRec, getReq)
+	if getRec.Code != http.StatusOK {
+		t.Fatalf("GET /hero/Comet: expected 200, got %d", getRec.Code)
+	}
+
+	metricsReq := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	metricsRec := httptest.NewRecorder()
+	router.ServeHTTP(metricsRec, metricsReq)
+	if metricsRec.Code != http.StatusOK {
+		t.Fatalf("GET /metrics: expected 200, got %d", metricsRec.Code)
+	}
+
+	// heroes_total (a gauge) and http_requests_total (a counter) are
+	// process-wide Prometheus metrics shared across every test in this package, so only their
+	// presence is checked here; hero_exhaustion is keyed by name, so
+	// Comet's own gauge value is safe to assert exactly.
+	body := metricsRec.Body.String()
+	for _, want := range []string{
+		`heroes_total{alive="true"}`,
+		`hero_exhaustion{name="Comet"} 0`,
+		`http_requests_total{`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected /metrics output to contain %q, got:\n%s", want, body)
+		}
+	}
+}
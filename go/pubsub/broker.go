@@ -0,0 +1,86 @@
+// Package pubsub provides a small in-process publish/subscribe broker used
+// to fan event structs out to streaming HTTP clients (SSE, WebSocket, ...).
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Broker fans published values of type T out to any number of subscribers.
+// The publisher encodes each value once and passes the bytes to Publish;
+// subscribers only ever copy those bytes, so a slow JSON encoder never runs
+// once per subscriber.
+type Broker[T any] struct {
+	mu          sync.RWMutex
+	nextID      uint64
+	subscribers map[uint64]*subscriber[T]
+}
+
+type subscriber[T any] struct {
+	ch      chan []byte
+	filter  func(T) bool
+	dropped uint64
+}
+
+// subscriberBuffer is the per-subscriber channel capacity. A slow subscriber
+// can fall behind by this many events before Publish starts dropping events
+// destined for it; the drop count is kept in the subscriber's dropped field,
+// which Broker does not expose.
+const subscriberBuffer = 100_000
+
+// NewBroker creates an empty Broker.
+func NewBroker[T any]() *Broker[T] {
+	return &Broker[T]{subscribers: make(map[uint64]*subscriber[T])}
+}
+
+// Subscribe registers a new subscriber and returns a channel of pre-encoded
+// payloads. When filter is non-nil, only values for which it returns true
+// are delivered. The channel is closed once ctx is done.
+//
+// Publish never blocks on a slow subscriber: if a subscriber's buffer is
+// full, the event is dropped for that subscriber and its drop counter is
+// incremented rather than applying backpressure to the publisher or to
+// other subscribers.
+func (b *Broker[T]) Subscribe(ctx context.Context, filter func(T) bool) <-chan []byte {
+	sub := &subscriber[T]{
+		ch:     make(chan []byte, subscriberBuffer),
+		filter: filter,
+	}
+
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subscribers[id] = sub
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subscribers, id)
+		b.mu.Unlock()
+		close(sub.ch)
+	}()
+
+	return sub.ch
+}
+
+// Publish fans encoded, the caller's already-encoded form of value, out to
+// every subscriber whose filter accepts value. A subscriber that can't keep up has
+// the event dropped for it rather than slowing down the others.
+func (b *Broker[T]) Publish(value T, encoded []byte) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, sub := range b.subscribers {
+		if sub.filter != nil && !sub.filter(value) {
+			continue
+		}
+		select {
+		case sub.ch <- encoded:
+		default:
+			atomic.AddUint64(&sub.dropped, 1)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Sentinel errors returned by a HeroRepository implementation. Handlers
+// translate these into the appropriate HTTP status rather than each
+// implementation hard-coding status codes itself.
+var (
+	ErrHeroNotFound    = errors.New("hero does not exist")
+	ErrLockUnavailable = errors.New("could not acquire the hero lock before the request timed out")
+
+	errHeroAlreadyDead = errors.New("hero already dead")
+	errHeroNotAlive    = errors.New("hero not alive")
+	errNoExhaustion    = errors.New("hero has no exhaustion to rest off")
+)
+
+// ErrHeroBusy is returned when a hero is still held by a calamity's hold
+// time. Until is when the hero is expected to free up, so callers can
+// populate a Retry-After header.
+type ErrHeroBusy struct {
+	Until time.Time
+}
+
+func (e *ErrHeroBusy) Error() string {
+	return fmt.Sprintf("hero is busy until %s", e.Until.Format(time.RFC3339))
+}
+
+// ErrHeroExists is returned by Insert when a hero with the requested name is
+// already tracked. Existing carries enough state for the caller to tell a
+// living name clash apart from a retired-by-death one, since those two
+// cases have always produced different response messages.
+type ErrHeroExists struct {
+	Existing hero
+}
+
+func (e *ErrHeroExists) Error() string {
+	return fmt.Sprintf("hero %q is already tracked", e.Existing.Name)
+}
+
+// HeroFilter narrows a List call to heroes matching every set field.
+type HeroFilter struct {
+	NameContains string
+	MinPower     int
+	Alive        *bool
+}
+
+// Matches reports whether h satisfies every criterion in f.
+func (f HeroFilter) Matches(h hero) bool {
+	if f.NameContains != "" && !strings.Contains(strings.ToLower(h.Name), strings.ToLower(f.NameContains)) {
+		return false
+	}
+	if h.PowerLevel < f.MinPower {
+		return false
+	}
+	if f.Alive != nil && h.Alive != *f.Alive {
+		return false
+	}
+	return true
+}
+
+// heroSortFields is the allow-list of tokens accepted by GET /v1/heroes's
+// sort query parameter, each paired with its ascending-order comparator. A
+// leading "-" on the token reverses it.
+var heroSortFields = map[string]func(a, b hero) bool{
+	"name":       func(a, b hero) bool { return a.Name < b.Name },
+	"powerlevel": func(a, b hero) bool { return a.PowerLevel < b.PowerLevel },
+	"exhaustion": func(a, b hero) bool { return a.Exhaustion < b.Exhaustion },
+}
+
+// sortHeroes sorts heroes in place according to token (e.g. "-powerlevel").
+// token is assumed to have already been validated against heroSortFields.
+func sortHeroes(heroesList []hero, token string) {
+	desc := strings.HasPrefix(token, "-")
+	less := heroSortFields[strings.TrimPrefix(token, "-")]
+	sort.Slice(heroesList, func(i, j int) bool {
+		if desc {
+			return less(heroesList[j], heroesList[i])
+		}
+		return less(heroesList[i], heroesList[j])
+	})
+}
+
+// ListResult is a single page of heroes plus enough metadata to describe the
+// full result set it was drawn from.
+type ListResult struct {
+	Heroes       []hero
+	TotalRecords int
+}
+
+// HeroRepository is the storage abstraction behind every hero-reading and
+// hero-mutating endpoint. memoryHeroRepository is currently the only
+// implementation.
+type HeroRepository interface {
+	Get(ctx context.Context, name string) (hero, error)
+	List(ctx context.Context, filter HeroFilter, page, pageSize int, sort string) (ListResult, error)
+	Insert(ctx context.Context, h hero) error
+	Update(ctx context.Context, name string, mutate func(*hero) error) error
+	Delete(ctx context.Context, name string) error
+
+	// RetireIfAlive atomically checks that name is still alive and removes
+	// it in the same operation, returning errHeroNotAlive otherwise, or
+	// *ErrHeroBusy while the hero is held by a calamity's hold time. It
+	// exists because a separate Update(check-alive)-then-Delete sequence
+	// leaves a gap a concurrent kill can land in, letting a retired-but-
+	// just-killed name's entry get deleted anyway.
+	RetireIfAlive(ctx context.Context, name string) error
+}
@@ -0,0 +1,119 @@
+package main
+
+import "context"
+
+// memoryHeroRepository implements HeroRepository on top of a heroStore,
+// reusing its per-hero locking as-is.
+type memoryHeroRepository struct {
+	store *heroStore
+}
+
+func newMemoryHeroRepository(store *heroStore) *memoryHeroRepository {
+	return &memoryHeroRepository{store: store}
+}
+
+func (m *memoryHeroRepository) Get(ctx context.Context, name string) (hero, error) {
+	entry, ok := m.store.entry(name)
+	if !ok {
+		return hero{}, ErrHeroNotFound
+	}
+	if !entry.rlock(ctx) {
+		return hero{}, ErrLockUnavailable
+	}
+	defer entry.mu.RUnlock()
+	return entry.data, nil
+}
+
+func (m *memoryHeroRepository) Insert(ctx context.Context, h hero) error {
+	entry := m.store.entryOrCreate(h.Name)
+	if !entry.lock(ctx) {
+		return ErrLockUnavailable
+	}
+	defer entry.mu.Unlock()
+
+	if entry.data.Name != "" {
+		return &ErrHeroExists{Existing: entry.data}
+	}
+	entry.data = h
+	return nil
+}
+
+func (m *memoryHeroRepository) Update(ctx context.Context, name string, mutate func(*hero) error) error {
+	entry, ok := m.store.entry(name)
+	if !ok {
+		return ErrHeroNotFound
+	}
+	if !entry.lock(ctx) {
+		return ErrLockUnavailable
+	}
+	defer entry.mu.Unlock()
+	if entry.isBusy() {
+		return &ErrHeroBusy{Until: entry.busyUntil}
+	}
+	return mutate(&entry.data)
+}
+
+func (m *memoryHeroRepository) Delete(ctx context.Context, name string) error {
+	if _, ok := m.store.entry(name); !ok {
+		return ErrHeroNotFound
+	}
+	m.store.remove(name)
+	return nil
+}
+
+func (m *memoryHeroRepository) RetireIfAlive(ctx context.Context, name string) error {
+	entry, ok := m.store.entry(name)
+	if !ok {
+		return ErrHeroNotFound
+	}
+	if !entry.lock(ctx) {
+		return ErrLockUnavailable
+	}
+	defer entry.mu.Unlock()
+	if !entry.data.Alive {
+		return errHeroNotAlive
+	}
+	if entry.isBusy() {
+		return &ErrHeroBusy{Until: entry.busyUntil}
+	}
+	m.store.remove(name)
+	return nil
+}
+
+func (m *memoryHeroRepository) List(ctx context.Context, filter HeroFilter, page, pageSize int, sort string) (ListResult, error) {
+	m.store.mu.RLock()
+	names := make([]string, 0, len(m.store.entries))
+	for name := range m.store.entries {
+		names = append(names, name)
+	}
+	m.store.mu.RUnlock()
+
+	matched := make([]hero, 0, len(names))
+	for _, name := range names {
+		entry, ok := m.store.entry(name)
+		if !ok {
+			continue
+		}
+		if !entry.rlock(ctx) {
+			return ListResult{}, ErrLockUnavailable
+		}
+		h := entry.data
+		entry.mu.RUnlock()
+		if filter.Matches(h) {
+			matched = append(matched, h)
+		}
+	}
+
+	sortHeroes(matched, sort)
+
+	total := len(matched)
+	start := (page - 1) * pageSize
+	if start > total {
+		start = total
+	}
+	end := start + pageSize
+	if end > total {
+		end = total
+	}
+	return ListResult{Heroes: matched[start:end], TotalRecords: total}, nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// heroesSingleflightSharedTotal counts responses served from a call another
+// request was already in flight for; heroesSingleflightLeaderTotal counts
+// the calls that actually hit heroRepo. Both are plain package-level
+// counters; they are not registered with Prometheus and do not appear at
+// GET /metrics.
+var (
+	heroesSingleflightSharedTotal uint64
+	heroesSingleflightLeaderTotal uint64
+)
+
+// heroGetGroup and heroListGroup are held by pointer, not value, so that
+// singleflightGet/singleflightList can copy the pointer into a local
+// variable once at the top of the call. That local copy is what every
+// closure spawned for that call references from then on, so reassigning
+// the package variable itself (as tests do between runs, to isolate their
+// dedup counters) can never race with a call that's already in flight
+// against the old Group.
+var heroGetGroup = &singleflight.Group{}
+var heroListGroup = &singleflight.Group{}
+
+// dedupCall tracks the context backing one in-flight singleflight call and
+// how many callers are still waiting on it. The call's context is only
+// canceled once every waiter has given up, which is what lets N concurrent
+// callers share one fetch while still honoring individual cancellation.
+type dedupCall struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	waiters int
+}
+
+type dedupCallTracker struct {
+	mu    sync.Mutex
+	calls map[string]*dedupCall
+}
+
+func newDedupCallTracker() *dedupCallTracker {
+	return &dedupCallTracker{calls: make(map[string]*dedupCall)}
+}
+
+// join registers the caller as a waiter on key's in-flight call, creating
+// it if this is the first waiter, and returns the call's shared context
+// plus whether this caller is the leader (the one who created it, and
+// whose fetch the rest are sharing). leave must be called exactly once
+// when the caller is done waiting. The shared context is bounded by
+// timeout (so the leader's fetch still respects lockTimeout like every
+// other handler) but can also be canceled earlier, as soon as the last
+// waiter gives up.
+func (t *dedupCallTracker) join(key string, timeout time.Duration) (*dedupCall, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	call, ok := t.calls[key]
+	if !ok {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		call = &dedupCall{ctx: ctx, cancel: cancel}
+		t.calls[key] = call
+	}
+	call.waiters++
+	return call, !ok
+}
+
+func (t *dedupCallTracker) leave(key string, call *dedupCall) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	call.waiters--
+	if call.waiters == 0 {
+		call.cancel()
+	}
+}
+
+// forget drops key's tracked call once the underlying fetch has completed,
+// so the next caller starts a fresh one instead of reusing a canceled
+// context.
+func (t *dedupCallTracker) forget(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.calls, key)
+}
+
+var heroGetCalls = newDedupCallTracker()
+var heroListCalls = newDedupCallTracker()
+
+// singleflightGet shares one heroRepo.Get per hero name across concurrent
+// callers. If every caller's context is canceled before the leader's fetch
+// completes, the fetch itself is canceled via the linked context tracked by
+// heroGetCalls.
+func singleflightGet(ctx context.Context, name string) (hero, error) {
+	group, calls, repo := heroGetGroup, heroGetCalls, heroRepo
+
+	call, isLeader := calls.join(name, lockTimeout)
+	defer calls.leave(name, call)
+
+	resultCh := group.DoChan(name, func() (interface{}, error) {
+		defer calls.forget(name)
+		return repo.Get(call.ctx, name)
+	})
+
+	select {
+	case res := <-resultCh:
+		recordSingleflightResult(isLeader)
+		if res.Err != nil {
+			return hero{}, res.Err
+		}
+		return res.Val.(hero), nil
+	case <-ctx.Done():
+		return hero{}, ctx.Err()
+	}
+}
+
+// heroListKey canonicalizes a List call's parameters into a stable cache
+// key, independent of query-parameter ordering.
+func heroListKey(filter HeroFilter, page, pageSize int, sort string) string {
+	alive := "any"
+	if filter.Alive != nil {
+		alive = fmt.Sprintf("%t", *filter.Alive)
+	}
+	canonical := fmt.Sprintf("name=%s&minPower=%d&alive=%s&page=%d&page_size=%d&sort=%s",
+		filter.NameContains, filter.MinPower, alive, page, pageSize, sort)
+
+	h := fnv.New64a()
+	h.Write([]byte(canonical))
+	return fmt.Sprintf("%x", h.Sum64())
+}
+
+// singleflightList shares one heroRepo.List per distinct query across
+// concurrent callers, with the same cancellation semantics as
+// singleflightGet.
+func singleflightList(ctx context.Context, filter HeroFilter, page, pageSize int, sort string) (ListResult, error) {
+	key := heroListKey(filter, page, pageSize, sort)
+	group, calls, repo := heroListGroup, heroListCalls, heroRepo
+
+	call, isLeader := calls.join(key, lockTimeout)
+	defer calls.leave(key, call)
+
+	resultCh := group.DoChan(key, func() (interface{}, error) {
+		defer calls.forget(key)
+		return repo.List(call.ctx, filter, page, pageSize, sort)
+	})
+
+	select {
+	case res := <-resultCh:
+		recordSingleflightResult(isLeader)
+		if res.Err != nil {
+			return ListResult{}, res.Err
+		}
+		return res.Val.(ListResult), nil
+	case <-ctx.Done():
+		return ListResult{}, ctx.Err()
+	}
+}
+
+// recordSingleflightResult updates the dedup-rate counters for one
+// singleflightGet/singleflightList caller. isLeader comes from
+// dedupCallTracker.join, which serializes every caller for the same key
+// through its own mutex — unlike singleflight.Group's own Result.Shared
+// field (true for every caller sharing a call, leader included), this
+// gives an unambiguous, race-free leader/follower split: exactly one
+// leader per call, the rest shared.
+func recordSingleflightResult(isLeader bool) {
+	if isLeader {
+		atomic.AddUint64(&heroesSingleflightLeaderTotal, 1)
+	} else {
+		atomic.AddUint64(&heroesSingleflightSharedTotal, 1)
+	}
+}
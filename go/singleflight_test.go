@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowGetRepository wraps a HeroRepository and makes Get block until
+// release is closed, counting how many times Get actually ran and whether
+// the context it was called with was ever canceled before finishing.
+type slowGetRepository struct {
+	HeroRepository
+	release       chan struct{}
+	calls         int32
+	sawCancelFlag int32
+}
+
+func (s *slowGetRepository) Get(ctx context.Context, name string) (hero, error) {
+	atomic.AddInt32(&s.calls, 1)
+	select {
+	case <-s.release:
+	case <-ctx.Done():
+		atomic.StoreInt32(&s.sawCancelFlag, 1)
+		return hero{}, ctx.Err()
+	}
+	return s.HeroRepository.Get(ctx, name)
+}
+
+// TestSingleflightGetSharesOneFetchAcrossConcurrentCallers fires many
+// concurrent Gets for the same hero while the backing fetch is slow and
+// checks that they're served by a single underlying call.
+func TestSingleflightGetSharesOneFetchAcrossConcurrentCallers(t *testing.T) {
+	newTestRouter()
+	makeEntryDirectly(t, "Orion", 10)
+
+	slow := &slowGetRepository{HeroRepository: heroRepo, release: make(chan struct{})}
+	heroRepo = slow
+
+	const callers = 20
+	var wg sync.WaitGroup
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := singleflightGet(context.Background(), "Orion")
+			errs[i] = err
+		}(i)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(slow.release)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("caller %d: unexpected error: %v", i, err)
+		}
+	}
+	if got := atomic.LoadInt32(&slow.calls); got != 1 {
+		t.Fatalf("expected exactly 1 underlying Get call, got %d", got)
+	}
+	if got := atomic.LoadUint64(&heroesSingleflightSharedTotal); got != callers-1 {
+		t.Fatalf("expected %d shared results, got %d", callers-1, got)
+	}
+}
+
+// TestSingleflightGetCancelsUnderlyingFetchWhenLastWaiterGivesUp checks that
+// once every caller waiting on a key has had its own context canceled, the
+// underlying fetch's context is canceled too rather than left running.
+func TestSingleflightGetCancelsUnderlyingFetchWhenLastWaiterGivesUp(t *testing.T) {
+	newTestRouter()
+	makeEntryDirectly(t, "Orion", 10)
+
+	slow := &slowGetRepository{HeroRepository: heroRepo, release: make(chan struct{})}
+	heroRepo = slow
+	defer close(slow.release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_, err := singleflightGet(ctx, "Orion")
+		if err == nil {
+			t.Error("expected an error once the caller's context was canceled")
+		}
+		close(done)
+	}()
+
+	waitUntil(t, func() bool { return atomic.LoadInt32(&slow.calls) == 1 })
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("singleflightGet did not return after its only caller's context was canceled")
+	}
+
+	waitUntil(t, func() bool { return atomic.LoadInt32(&slow.sawCancelFlag) == 1 })
+}
+
+// makeEntryDirectly seeds a hero straight into the store, bypassing the
+// HTTP layer so tests can swap heroRepo for a decorator afterwards.
+func makeEntryDirectly(t *testing.T, name string, power int) {
+	t.Helper()
+	if err := heroRepo.Insert(context.Background(), hero{Name: name, PowerLevel: power, Alive: true}); err != nil {
+		t.Fatalf("seeding hero %q: %v", name, err)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fbartkow321/interview/go/pubsub"
+	"github.com/gorilla/websocket"
+)
+
+// HeroEvent describes a single observable moment in a hero's lifecycle,
+// published over /v1/trace for any connected client to observe in real
+// time.
+type HeroEvent struct {
+	Type string    `json:"type"`
+	Hero string    `json:"hero"`
+	Time time.Time `json:"time"`
+}
+
+// Event types published by the handlers in hero.go.
+const (
+	eventCreated          = "created"
+	eventRested           = "rested"
+	eventCalamityResolved = "calamity_resolved"
+	eventExhausted        = "exhausted"
+	eventRetired          = "retired"
+	eventKilled           = "killed"
+)
+
+var heroEvents = pubsub.NewBroker[HeroEvent]()
+
+// publishHeroEvent encodes evt once and hands it to the broker for fan-out.
+func publishHeroEvent(eventType, heroName string) {
+	evt := HeroEvent{Type: eventType, Hero: heroName, Time: time.Now()}
+	encoded, err := json.Marshal(evt)
+	if err != nil {
+		log.Printf("trace: failed to encode %s event for %q: %v", eventType, heroName, err)
+		return
+	}
+	heroEvents.Publish(evt, encoded)
+}
+
+// traceFilter builds a HeroEvent predicate from a comma-separated
+// ?types=calamity_resolved,killed query parameter. An empty or absent
+// parameter matches every event.
+func traceFilter(r *http.Request) func(HeroEvent) bool {
+	raw := r.URL.Query().Get("types")
+	if raw == "" {
+		return nil
+	}
+	wanted := make(map[string]bool)
+	for _, t := range strings.Split(raw, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			wanted[t] = true
+		}
+	}
+	return func(evt HeroEvent) bool {
+		return wanted[evt.Type]
+	}
+}
+
+// handleTraceSSE streams hero events as Server-Sent Events until the client
+// disconnects.
+func handleTraceSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		httpError(w, r, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	ch := heroEvents.Subscribe(r.Context(), traceFilter(r))
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case payload, open := <-ch:
+			if !open {
+				return
+			}
+			fmt.Fprintf(w, "data: %s\n\n", payload)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+var traceUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// Trace is a read-only firehose with no cross-site side effects, so
+	// accepting any origin is acceptable here.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// handleTraceWS is the WebSocket equivalent of handleTraceSSE.
+func handleTraceWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := traceUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("trace: websocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	ch := heroEvents.Subscribe(r.Context(), traceFilter(r))
+	for payload := range ch {
+		if err := conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+			return
+		}
+	}
+}